@@ -0,0 +1,295 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// APIURL is the base URL for Cloudflare API v4 requests.
+const APIURL = "https://api.cloudflare.com/client/v4"
+
+// defaultUserAgent is sent on every request unless overridden with
+// WithUserAgent.
+const defaultUserAgent = "cloudflare-go"
+
+// ResponseInfo holds a single error or message entry from a Cloudflare API
+// response's `errors` or `messages` array.
+type ResponseInfo struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Response is the envelope Cloudflare wraps every JSON API response in.
+type Response struct {
+	Success  bool           `json:"success"`
+	Errors   []ResponseInfo `json:"errors"`
+	Messages []ResponseInfo `json:"messages"`
+}
+
+// Option configures an API client. Options are applied in order by New and
+// NewWithAPIToken.
+type Option func(*API) error
+
+// API holds the configuration for the current API client.
+type API struct {
+	APIKey   string
+	APIEmail string
+	APIToken string
+
+	BaseURL   string
+	UserAgent string
+
+	headers    http.Header
+	httpClient *http.Client
+
+	retryPolicy   RetryPolicy
+	retryHook     RetryHook
+	requestLogger RequestLogger
+}
+
+// WithUserAgent sets the User-Agent sent on every request, overriding the
+// package default.
+func WithUserAgent(userAgent string) Option {
+	return func(api *API) error {
+		api.UserAgent = userAgent
+		return nil
+	}
+}
+
+// WithHTTPClient lets a caller supply their own *http.Client rather than
+// using the package default.
+func WithHTTPClient(client *http.Client) Option {
+	return func(api *API) error {
+		api.httpClient = client
+		return nil
+	}
+}
+
+// New creates a new Cloudflare v4 API client using an API key and email
+// address for authentication.
+func New(key, email string, opts ...Option) (*API, error) {
+	if key == "" || email == "" {
+		return nil, errors.New(errEmptyCredentials)
+	}
+
+	return newClient(opts, func(api *API) {
+		api.APIKey = key
+		api.APIEmail = email
+	})
+}
+
+// NewWithAPIToken creates a new Cloudflare v4 API client using an API
+// Token for authentication.
+func NewWithAPIToken(token string, opts ...Option) (*API, error) {
+	if token == "" {
+		return nil, errors.New(errEmptyAPIToken)
+	}
+
+	return newClient(opts, func(api *API) {
+		api.APIToken = token
+	})
+}
+
+func newClient(opts []Option, withCredentials func(*API)) (*API, error) {
+	api := &API{
+		BaseURL:     APIURL,
+		UserAgent:   defaultUserAgent,
+		headers:     make(http.Header),
+		httpClient:  http.DefaultClient,
+		retryPolicy: defaultRetryPolicy,
+	}
+	withCredentials(api)
+
+	for _, opt := range opts {
+		if err := opt(api); err != nil {
+			return nil, err
+		}
+	}
+
+	return api, nil
+}
+
+// authenticate sets the credential headers used by every request.
+func (api *API) authenticate(req *http.Request) {
+	if api.APIToken != "" {
+		req.Header.Set("Authorization", "Bearer "+api.APIToken)
+		return
+	}
+
+	req.Header.Set("X-Auth-Key", api.APIKey)
+	req.Header.Set("X-Auth-Email", api.APIEmail)
+}
+
+// makeRequest makes a request to the given API endpoint without a
+// cancellable context; callers that need cancellation should use
+// makeRequestContext.
+func (api *API) makeRequest(method, uri string, body interface{}) ([]byte, error) {
+	return api.makeRequestContext(context.Background(), method, uri, body)
+}
+
+// makeRequestContext performs an HTTP request against the Cloudflare API,
+// retrying retryable failures per api.retryPolicy (see retry.go), and
+// decoding any error response into a typed error (RequestError,
+// RatelimitError, ServiceError, ...) that supports errors.Is/errors.As
+// (see errors.go).
+func (api *API) makeRequestContext(ctx context.Context, method, uri string, body interface{}) ([]byte, error) {
+	var respBody []byte
+
+	err := api.withRetry(ctx, method, func() (bool, error) {
+		bs, bodyWritten, reqErr := api.request(ctx, method, uri, body)
+		if reqErr == nil {
+			respBody = bs
+		}
+		return bodyWritten, reqErr
+	})
+
+	return respBody, err
+}
+
+// bodyWriteTracker wraps a request body reader and records whether any
+// bytes of it were read (and therefore written to the transport), so the
+// retry loop can tell whether it's safe to retry a non-idempotent method
+// after a transport-level failure.
+type bodyWriteTracker struct {
+	io.Reader
+	written *bool
+}
+
+func (t *bodyWriteTracker) Read(p []byte) (int, error) {
+	n, err := t.Reader.Read(p)
+	if n > 0 {
+		*t.written = true
+	}
+	return n, err
+}
+
+// transportError wraps a failure from the HTTP round trip itself -- DNS,
+// connection refused, TLS, a timeout before any response was received --
+// as distinct from a response status Cloudflare returned. retryableError
+// in retry.go treats it as always retryable, subject to the same
+// bodyWritten/retryableMethod guard applied to a 429/503.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+// request performs a single attempt of an HTTP request and reports whether
+// the request body started streaming to the transport before any error
+// occurred. Once a response has been received, bodyWritten is always
+// reported as true, whether or not the request had a body.
+func (api *API) request(ctx context.Context, method, uri string, body interface{}) (respBody []byte, bodyWritten bool, err error) {
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, jErr := json.Marshal(body)
+		if jErr != nil {
+			return nil, false, fmt.Errorf("%s: %w", errMakeRequestError, jErr)
+		}
+		reqBody = &bodyWriteTracker{Reader: bytes.NewReader(jsonBody), written: &bodyWritten}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, api.BaseURL+uri, reqBody)
+	if err != nil {
+		return nil, bodyWritten, fmt.Errorf("%s: %w", errMakeRequestError, err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", api.UserAgent)
+	for k, v := range api.headers {
+		req.Header[k] = v
+	}
+	api.authenticate(req)
+
+	start := time.Now()
+	resp, err := api.httpClient.Do(req)
+	if err != nil {
+		return nil, bodyWritten, &transportError{err: fmt.Errorf("%s: %w", errMakeRequestError, err)}
+	}
+	defer resp.Body.Close()
+	bodyWritten = true
+
+	respBody, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, bodyWritten, fmt.Errorf("%s: %w", errMakeRequestError, err)
+	}
+
+	api.logRequest(req, resp, start)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, bodyWritten, api.buildError(resp, respBody)
+	}
+
+	return respBody, bodyWritten, nil
+}
+
+// logRequest invokes api.requestLogger, if WithRequestLogger registered
+// one, with the outcome of a single request attempt -- called from
+// request for every attempt that receives a response and whose body is
+// read, whatever its status, including retried ones. An attempt that fails
+// in the round trip itself, or while reading the response body, is not
+// logged.
+func (api *API) logRequest(req *http.Request, resp *http.Response, start time.Time) {
+	if api.requestLogger == nil {
+		return
+	}
+
+	api.requestLogger(RequestInfo{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		RayID:      resp.Header.Get("CF-Ray"),
+		Duration:   time.Since(start),
+	})
+}
+
+// buildError decodes an error response from Cloudflare into an *Error,
+// then wraps it in the concrete error type matching its HTTP status code
+// (see newTypedError in errors.go) so callers can use errors.Is/errors.As
+// against both the status-based sentinels (ErrNotFound, ErrRateLimited,
+// ...) and the well-known internal-error-code sentinels
+// (ErrRecordNotFound, ...), and the retry loop in retry.go can recognize
+// RatelimitError/ServiceError.
+//
+// When the response's Content-Type isn't application/json, the body is
+// captured as a raw snippet (see newRawBodyError and the RawBody field doc
+// on Error in errors.go for why) instead of failing with
+// errUnmarshalErrorBody.
+func (api *API) buildError(resp *http.Response, body []byte) error {
+	contentType := resp.Header.Get("Content-Type")
+
+	var cfErr *Error
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "application/json" {
+		cfErr = newRawBodyError(resp.StatusCode, contentType, body)
+	} else {
+		var apiResp Response
+		if err := json.Unmarshal(body, &apiResp); err != nil {
+			return fmt.Errorf("%s: %w", errUnmarshalErrorBody, err)
+		}
+
+		cfErr = &Error{
+			StatusCode: resp.StatusCode,
+			Errors:     apiResp.Errors,
+			ErrorCodes: errorCodes(apiResp.Errors),
+		}
+	}
+	cfErr.rawRetryAfter = resp.Header.Get("Retry-After")
+	cfErr.populateTraceIDs(resp.Header)
+
+	return newTypedError(cfErr)
+}
+
+func errorCodes(infos []ResponseInfo) []int {
+	codes := make([]int, 0, len(infos))
+	for _, info := range infos {
+		codes = append(codes, info.Code)
+	}
+	return codes
+}
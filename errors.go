@@ -1,6 +1,7 @@
 package cloudflare
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -8,8 +9,8 @@ import (
 
 // Error messages.
 const (
-	errEmptyCredentials          = "invalid credentials: key & email must not be empty" //nolint:gosec,unused
-	errEmptyAPIToken             = "invalid credentials: API Token must not be empty"   //nolint:gosec,unused
+	errEmptyCredentials          = "invalid credentials: key & email must not be empty" //nolint:gosec
+	errEmptyAPIToken             = "invalid credentials: API Token must not be empty"   //nolint:gosec
 	errInternalServiceError      = "internal service error"
 	errMakeRequestError          = "error from makeRequest"
 	errUnmarshalError            = "error unmarshalling the JSON response"
@@ -23,13 +24,38 @@ const (
 	errInvalidZoneIdentifer      = "invalid zone identifier: %s"
 )
 
+// maxRawBodySnippet caps how much of a non-JSON error body is retained on
+// Error.RawBody and included in Error.Error(), so a misbehaving edge proxy
+// returning a full HTML page doesn't blow up logs.
+const maxRawBodySnippet = 2048
+
 type Error struct {
 	StatusCode int
 
 	Errors     []ResponseInfo
 	ErrorCodes []int
 
-	RayID string
+	// rayID and requestID are populated by makeRequest from the CF-Ray and
+	// CF-Request-ID response headers. Use the RayID and RequestID accessor
+	// methods below rather than referencing these directly.
+	rayID     string
+	requestID string
+
+	// RawBody holds the raw response body, truncated to maxRawBodySnippet,
+	// when an error response did not carry an `application/json`
+	// Content-Type. Cloudflare's API is fronted by an edge/CDN layer that
+	// can return HTML or plaintext for 502/503/520-527 responses, typically
+	// during a platform incident or a misbehaving origin.
+	RawBody []byte
+
+	// ContentType is the response's Content-Type header, recorded whenever
+	// RawBody is set so callers can tell an HTML incident page from a
+	// plaintext one.
+	ContentType string
+
+	// rawRetryAfter is the verbatim `Retry-After` header value captured by
+	// makeRequest, if any. It is consulted by the retry loop in retry.go.
+	rawRetryAfter string
 }
 
 func (e Error) Error() string {
@@ -48,63 +74,210 @@ func (e Error) Error() string {
 		errMessages = append(errMessages, m)
 	}
 
-	return errString + strings.Join(errMessages, ", ")
+	if len(errMessages) == 0 && len(e.RawBody) > 0 {
+		errString = fmt.Sprintf("HTTP status %d: non-JSON response (content-type %q): %s", e.StatusCode, e.ContentType, e.RawBody)
+	} else {
+		errString += strings.Join(errMessages, ", ")
+	}
+
+	if e.rayID != "" {
+		errString = fmt.Sprintf("%s (Cloudflare Ray ID: %s)", errString, e.rayID)
+	}
+	if e.requestID != "" {
+		errString = fmt.Sprintf("%s (Request ID: %s)", errString, e.requestID)
+	}
+
+	return errString
+}
+
+// RayID returns the `CF-Ray` identifier from the response that produced
+// this error, if any. Include it when filing a Cloudflare support ticket --
+// support uses it to locate the request server-side.
+func (e *Error) RayID() string {
+	return e.rayID
+}
+
+// RequestID returns the `CF-Request-ID` identifier from the response that
+// produced this error, if any.
+func (e *Error) RequestID() string {
+	return e.requestID
+}
+
+// populateTraceIDs records the Cloudflare trace identifiers from the
+// response headers (CF-Ray and CF-Request-ID) on e. makeRequest calls this
+// for every error response so that RayID and RequestID are always
+// populated when Cloudflare supplies them.
+func (e *Error) populateTraceIDs(h http.Header) {
+	e.rayID = h.Get("CF-Ray")
+	e.requestID = h.Get("CF-Request-ID")
+}
+
+// newRawBodyError builds an *Error from a non-JSON error response, keyed
+// off the raw response body and Content-Type rather than the documented
+// JSON error envelope. makeRequest calls this instead of unmarshalling
+// (and failing with errUnmarshalErrorBody) whenever an error response's
+// Content-Type isn't application/json.
+func newRawBodyError(statusCode int, contentType string, body []byte) *Error {
+	if len(body) > maxRawBodySnippet {
+		body = body[:maxRawBodySnippet]
+	}
+
+	return &Error{
+		StatusCode:  statusCode,
+		ContentType: contentType,
+		RawBody:     body,
+	}
+}
+
+// Sentinel errors that callers can match against with errors.Is, regardless
+// of which concrete wrapper type (RequestError, RatelimitError, ...) the
+// client returned.
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrInternal     = errors.New("internal error")
+)
+
+// Sentinel errors for well-known Cloudflare internal error codes. Unlike
+// the status-based sentinels above, these match regardless of the HTTP
+// status Cloudflare chose to report the underlying condition with -- code
+// 81044, for example, has been observed on both 404 and 400 responses.
+var (
+	ErrRecordNotFound      = &internalCodeError{code: 81044, msg: "record does not exist"}
+	ErrAuthenticationError = &internalCodeError{code: 10000, msg: "authentication error"}
+)
+
+// internalCodeError is a comparable sentinel keyed on a Cloudflare internal
+// error code. It is never returned directly; it only ever appears as the
+// target of an errors.Is check against a wrappedError.
+type internalCodeError struct {
+	code int
+	msg  string
+}
+
+func (e *internalCodeError) Error() string { return e.msg }
+
+// wrappedError holds the shared machinery for the status-code-specific
+// error types below: the underlying *Error, and the sentinel that
+// identifies this wrapper's status code for errors.Is.
+type wrappedError struct {
+	cloudflareError *Error
+	sentinel        error
+}
+
+func (e wrappedError) Error() string {
+	return e.cloudflareError.Error()
+}
+
+// Unwrap allows errors.As to reach the underlying *Error, e.g.
+// `var ce *cloudflare.Error; errors.As(err, &ce)`.
+func (e wrappedError) Unwrap() error {
+	return e.cloudflareError
+}
+
+// Is reports whether target is this wrapper's status-based sentinel (e.g.
+// ErrNotFound), or one of the internal-error-code sentinels (e.g.
+// ErrRecordNotFound) whose code matches one of the errors Cloudflare
+// returned.
+func (e wrappedError) Is(target error) bool {
+	if target == e.sentinel {
+		return true
+	}
+
+	if ice, ok := target.(*internalCodeError); ok {
+		return e.cloudflareError.InternalErrorCodeIs(ice.code)
+	}
+
+	return false
+}
+
+// CloudflareError returns the underlying *Error, giving callers access to
+// the HTTP status code, RayID, and internal error codes without a type
+// assertion on the concrete wrapper type.
+func (e wrappedError) CloudflareError() *Error {
+	return e.cloudflareError
 }
 
 // RequestError is for 4xx errors that we encounter not covered elsewhere
 // (generally bad payloads).
 type RequestError struct {
-	cloudflareError *Error
+	wrappedError
 }
 
-func (e RequestError) Error() string {
-	return e.cloudflareError.Error()
+func newRequestError(e *Error) RequestError {
+	return RequestError{wrappedError{cloudflareError: e, sentinel: ErrBadRequest}}
 }
 
 // RatelimitError is for HTTP 429s where the service is telling the client to
 // slow down.
 type RatelimitError struct {
-	cloudflareError *Error
+	wrappedError
 }
 
-func (e RatelimitError) Error() string {
-	return e.cloudflareError.Error()
+func newRatelimitError(e *Error) RatelimitError {
+	return RatelimitError{wrappedError{cloudflareError: e, sentinel: ErrRateLimited}}
 }
 
 // ServiceError is a handler for 5xx errors returned to the client.
 type ServiceError struct {
-	cloudflareError *Error
+	wrappedError
 }
 
-func (e ServiceError) Error() string {
-	return e.cloudflareError.Error()
+func newServiceError(e *Error) ServiceError {
+	return ServiceError{wrappedError{cloudflareError: e, sentinel: ErrInternal}}
 }
 
 // AuthenticationError is for HTTP 401 responses.
 type AuthenticationError struct {
-	cloudflareError *Error
+	wrappedError
 }
 
-func (e AuthenticationError) Error() string {
-	return e.cloudflareError.Error()
+func newAuthenticationError(e *Error) AuthenticationError {
+	return AuthenticationError{wrappedError{cloudflareError: e, sentinel: ErrUnauthorized}}
 }
 
 // AuthorizationError is for HTTP 403 responses.
 type AuthorizationError struct {
-	cloudflareError *Error
+	wrappedError
 }
 
-func (e AuthorizationError) Error() string {
-	return e.cloudflareError.Error()
+func newAuthorizationError(e *Error) AuthorizationError {
+	return AuthorizationError{wrappedError{cloudflareError: e, sentinel: ErrForbidden}}
 }
 
 // NotFoundError is for HTTP 404 responses.
 type NotFoundError struct {
-	cloudflareError *Error
+	wrappedError
 }
 
-func (e NotFoundError) Error() string {
-	return e.cloudflareError.Error()
+func newNotFoundError(e *Error) NotFoundError {
+	return NotFoundError{wrappedError{cloudflareError: e, sentinel: ErrNotFound}}
+}
+
+// newTypedError wraps e in the concrete error type matching its HTTP
+// status code, so that makeRequest can return a single value that
+// supports errors.Is/errors.As against both the status-based sentinels
+// above and the well-known internal-error-code sentinels.
+func newTypedError(e *Error) error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		return newAuthenticationError(e)
+	case e.StatusCode == http.StatusForbidden:
+		return newAuthorizationError(e)
+	case e.StatusCode == http.StatusNotFound:
+		return newNotFoundError(e)
+	case e.StatusCode == http.StatusTooManyRequests:
+		return newRatelimitError(e)
+	case e.ClientError():
+		return newRequestError(e)
+	case e.ServiceError():
+		return newServiceError(e)
+	default:
+		return e
+	}
 }
 
 // HTTPStatusCode exposes the HTTP status from the error response encountered.
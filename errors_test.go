@@ -0,0 +1,85 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMakeRequestContextWrapsStatusSentinels(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusBadRequest, ErrBadRequest},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrForbidden},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusInternalServerError, ErrInternal},
+	}
+
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(tt.status)
+			w.Write([]byte(`{"success":false,"errors":[{"code":1,"message":"nope"}]}`))
+		}))
+
+		api, err := New("key", "email@example.com", WithRetryPolicy(RetryPolicy{MaxRetries: 0}))
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		api.BaseURL = srv.URL
+
+		_, reqErr := api.makeRequestContext(context.Background(), http.MethodGet, "/zones", nil)
+		srv.Close()
+
+		if reqErr == nil {
+			t.Fatalf("status %d: expected an error", tt.status)
+		}
+		if !errors.Is(reqErr, tt.want) {
+			t.Errorf("status %d: errors.Is(err, %v) = false, want true (err: %v)", tt.status, tt.want, reqErr)
+		}
+
+		var cfErr *Error
+		if !errors.As(reqErr, &cfErr) {
+			t.Errorf("status %d: errors.As into *Error failed", tt.status)
+			continue
+		}
+		if cfErr.StatusCode != tt.status {
+			t.Errorf("status %d: cfErr.StatusCode = %d", tt.status, cfErr.StatusCode)
+		}
+	}
+}
+
+func TestMakeRequestContextWrapsInternalCodeSentinel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"success":false,"errors":[{"code":81044,"message":"record does not exist"}]}`))
+	}))
+	defer srv.Close()
+
+	api, err := New("key", "email@example.com", WithRetryPolicy(RetryPolicy{MaxRetries: 0}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.BaseURL = srv.URL
+
+	_, reqErr := api.makeRequestContext(context.Background(), http.MethodGet, "/zones/1/dns_records/1", nil)
+	if reqErr == nil {
+		t.Fatal("expected an error")
+	}
+
+	// Code 81044 was reported over HTTP 400 here, not 404, but
+	// ErrRecordNotFound should still match.
+	if !errors.Is(reqErr, ErrRecordNotFound) {
+		t.Errorf("errors.Is(err, ErrRecordNotFound) = false, want true (err: %v)", reqErr)
+	}
+	if !errors.Is(reqErr, ErrBadRequest) {
+		t.Errorf("errors.Is(err, ErrBadRequest) = false, want true (err: %v)", reqErr)
+	}
+}
@@ -0,0 +1,65 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMakeRequestContextHandlesNonJSONErrorBody verifies that a 502 with an
+// HTML body from an edge/CDN incident page is captured as a RawBody
+// snippet -- via the real request path, not just newRawBodyError in
+// isolation -- rather than failing with errUnmarshalErrorBody, and that it
+// still comes back as a ServiceError so the retry loop recognizes it.
+func TestMakeRequestContextHandlesNonJSONErrorBody(t *testing.T) {
+	const html = `<html><body><h1>502 Bad Gateway</h1></body></html>`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(html))
+	}))
+	defer srv.Close()
+
+	api, err := New("key", "email@example.com", WithRetryPolicy(RetryPolicy{MaxRetries: 0}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.BaseURL = srv.URL
+
+	_, reqErr := api.makeRequestContext(context.Background(), http.MethodGet, "/zones", nil)
+	if reqErr == nil {
+		t.Fatal("expected an error")
+	}
+
+	var svcErr ServiceError
+	if !errors.As(reqErr, &svcErr) {
+		t.Fatalf("expected a ServiceError, got %T: %v", reqErr, reqErr)
+	}
+
+	var cfErr *Error
+	if !errors.As(reqErr, &cfErr) {
+		t.Fatalf("errors.As into *Error failed")
+	}
+	if cfErr.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("ContentType = %q", cfErr.ContentType)
+	}
+	if string(cfErr.RawBody) != html {
+		t.Errorf("RawBody = %q, want %q", cfErr.RawBody, html)
+	}
+	if !strings.Contains(reqErr.Error(), "502 Bad Gateway") {
+		t.Errorf("Error() = %q, want it to include the raw body snippet", reqErr.Error())
+	}
+}
+
+func TestNewRawBodyErrorTruncates(t *testing.T) {
+	body := strings.Repeat("x", maxRawBodySnippet+100)
+
+	e := newRawBodyError(http.StatusServiceUnavailable, "text/plain", []byte(body))
+	if len(e.RawBody) != maxRawBodySnippet {
+		t.Errorf("RawBody length = %d, want %d", len(e.RawBody), maxRawBodySnippet)
+	}
+}
@@ -0,0 +1,30 @@
+package cloudflare
+
+import "time"
+
+// RequestInfo describes a single HTTP request/response exchange made by
+// the client, passed to the callback registered via WithRequestLogger.
+type RequestInfo struct {
+	Method     string
+	URL        string
+	StatusCode int
+	RayID      string
+	Duration   time.Duration
+}
+
+// RequestLogger receives a RequestInfo for every request attempt that gets
+// an HTTP response, whatever its status. Attempts that fail without a
+// response (a transport error) or whose response body cannot be read are
+// not reported.
+type RequestLogger func(RequestInfo)
+
+// WithRequestLogger registers a callback that api.request (in
+// cloudflare.go) invokes once per HTTP attempt, after the response body
+// has been read, including attempts the retry loop in retry.go later
+// retries.
+func WithRequestLogger(logger RequestLogger) Option {
+	return func(api *API) error {
+		api.requestLogger = logger
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMakeRequestContextPopulatesTraceIDs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("CF-Ray", "1234-ABC")
+		w.Header().Set("CF-Request-ID", "req-5678")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"success":false,"errors":[{"code":1000,"message":"not found"}]}`))
+	}))
+	defer srv.Close()
+
+	api, err := New("key", "email@example.com", WithRetryPolicy(RetryPolicy{MaxRetries: 0}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.BaseURL = srv.URL
+
+	_, reqErr := api.makeRequestContext(context.Background(), http.MethodGet, "/zones/does-not-exist", nil)
+	if reqErr == nil {
+		t.Fatal("expected an error")
+	}
+
+	var cfErr *Error
+	if !errors.As(reqErr, &cfErr) {
+		t.Fatalf("errors.As into *Error failed")
+	}
+	if cfErr.RayID() != "1234-ABC" {
+		t.Errorf("RayID() = %q, want %q", cfErr.RayID(), "1234-ABC")
+	}
+	if cfErr.RequestID() != "req-5678" {
+		t.Errorf("RequestID() = %q, want %q", cfErr.RequestID(), "req-5678")
+	}
+
+	msg := reqErr.Error()
+	if !strings.Contains(msg, "1234-ABC") || !strings.Contains(msg, "req-5678") {
+		t.Errorf("Error() = %q, want it to include both trace IDs", msg)
+	}
+}
+
+func TestWithRequestLoggerFiresForEveryAttempt(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"success":false,"errors":[{"code":1,"message":"nope"}]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("CF-Ray", "ray-2")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var logged []RequestInfo
+	api, err := New("key", "email@example.com",
+		WithRetryPolicy(RetryPolicy{MaxRetries: 1, MinRetryDelay: 0, MaxRetryDelay: 0}),
+		WithRequestLogger(func(info RequestInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			logged = append(logged, info)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.BaseURL = srv.URL
+
+	if _, err := api.makeRequestContext(context.Background(), http.MethodGet, "/zones", nil); err != nil {
+		t.Fatalf("makeRequestContext: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(logged) != 2 {
+		t.Fatalf("expected the logger to fire for both attempts, got %d calls: %+v", len(logged), logged)
+	}
+	if logged[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("logged[0].StatusCode = %d, want 503", logged[0].StatusCode)
+	}
+	if logged[1].StatusCode != http.StatusOK || logged[1].RayID != "ray-2" {
+		t.Errorf("logged[1] = %+v, want status 200 and RayID ray-2", logged[1])
+	}
+}
@@ -0,0 +1,207 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Defaults used when a client is constructed without an explicit
+// WithRetryPolicy Option.
+const (
+	defaultMaxRetries    = 3
+	defaultMinRetryDelay = 1 * time.Second
+	defaultMaxRetryDelay = 30 * time.Second
+)
+
+// defaultRetryPolicy is the policy New and NewWithAPIToken install on every
+// client; WithRetryPolicy replaces it outright.
+var defaultRetryPolicy = RetryPolicy{
+	MaxRetries:    defaultMaxRetries,
+	MinRetryDelay: defaultMinRetryDelay,
+	MaxRetryDelay: defaultMaxRetryDelay,
+	Jitter:        true,
+}
+
+// RetryPolicy controls how makeRequest retries a request that fails with a
+// retryable error (an HTTP 429 or 5xx, or a transport error); POST and
+// PATCH are only retried in the narrower cases retryableMethod describes.
+// New and NewWithAPIToken
+// initialize API.retryPolicy to defaultRetryPolicy; WithRetryPolicy
+// replaces it outright, so a zero RetryPolicy passed to WithRetryPolicy
+// disables retries rather than falling back to the default.
+type RetryPolicy struct {
+	// MaxRetries is the number of retry attempts made after the initial
+	// request fails. Zero disables retries.
+	MaxRetries int
+
+	// MinRetryDelay and MaxRetryDelay bound the exponential backoff used
+	// between attempts when the response carries no `Retry-After` header.
+	MinRetryDelay time.Duration
+	MaxRetryDelay time.Duration
+
+	// Jitter, when true, randomizes each computed backoff delay (full
+	// jitter) so that multiple clients retrying the same request don't
+	// stay in lockstep.
+	Jitter bool
+}
+
+// RetryHook is invoked once per retried attempt, after the attempt has
+// failed but before the client sleeps ahead of the next one. It is not
+// called for the final attempt, whether that attempt succeeds or exhausts
+// the policy's MaxRetries.
+type RetryHook func(attempt int, err error, delay time.Duration)
+
+// WithRetryPolicy overrides the client's default retry behaviour (three
+// retries after the initial attempt, exponential backoff between one and
+// thirty seconds, jittered).
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(api *API) error {
+		api.retryPolicy = policy
+		return nil
+	}
+}
+
+// WithRetryHook registers a callback invoked for every retried request,
+// letting callers observe retries (for metrics or logging) without
+// wrapping the client's transport.
+func WithRetryHook(hook RetryHook) Option {
+	return func(api *API) error {
+		api.retryHook = hook
+		return nil
+	}
+}
+
+// withRetry runs do, retrying according to api.retryPolicy whenever do
+// returns a retryable error. do reports bodyWritten as true once it has
+// started streaming the request body to the transport, so that
+// non-idempotent methods are only retried when it's safe to do so. ctx
+// cancellation is honoured between attempts. It is called by
+// makeRequestContext in cloudflare.go around a single request attempt.
+func (api *API) withRetry(ctx context.Context, method string, do func() (bodyWritten bool, err error)) error {
+	policy := api.retryPolicy
+
+	var err error
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
+		var bodyWritten bool
+		bodyWritten, err = do()
+		if err == nil {
+			return nil
+		}
+
+		if attempt == policy.MaxRetries {
+			return err
+		}
+
+		retry, delay, hasRetryAfter := retryableError(err)
+		if !retry || !retryableMethod(method, bodyWritten, hasRetryAfter) {
+			return err
+		}
+
+		if !hasRetryAfter {
+			delay = backoff(policy, attempt+1)
+		}
+
+		if api.retryHook != nil {
+			api.retryHook(attempt+1, err, delay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	return err
+}
+
+// retryableError reports whether err is retryable, and the delay
+// requested via a `Retry-After` header on the underlying response, if one
+// was present and authoritative for retrying a non-idempotent method
+// (see retryableMethod).
+//
+// Three cases are retryable: a RatelimitError (429, always retryable on
+// its own schedule), a ServiceError (5xx), and a transportError -- a
+// failure from the HTTP round trip itself (DNS, connection refused, TLS,
+// timeout before a response was received), wrapped by request in
+// cloudflare.go. hasRetryAfter is only ever true for the RatelimitError
+// and 503 ServiceError cases Cloudflare documents emitting the header
+// for; a stray Retry-After on some other 5xx must not be treated as
+// license to retry a non-idempotent method.
+func retryableError(err error) (retry bool, retryAfter time.Duration, hasRetryAfter bool) {
+	var rlErr RatelimitError
+	if errors.As(err, &rlErr) {
+		d, ok := rlErr.cloudflareError.retryAfter()
+		return true, d, ok
+	}
+
+	var svcErr ServiceError
+	if errors.As(err, &svcErr) {
+		d, ok := svcErr.cloudflareError.retryAfter()
+		return true, d, ok && svcErr.cloudflareError.StatusCode == http.StatusServiceUnavailable
+	}
+
+	var transportErr *transportError
+	if errors.As(err, &transportErr) {
+		return true, 0, false
+	}
+
+	return false, 0, false
+}
+
+// retryableMethod reports whether a request for method may be retried.
+// GET/HEAD/PUT/DELETE are idempotent and always eligible. POST/PATCH are
+// only retried when no bytes of the body were written before the
+// transport failed, or when the response explicitly asked for a retry via
+// `Retry-After` (429/503) -- Cloudflare won't re-apply the same mutation
+// twice in that case.
+func retryableMethod(method string, bodyWritten bool, hasRetryAfter bool) bool {
+	switch method {
+	case http.MethodPost, http.MethodPatch:
+		return !bodyWritten || hasRetryAfter
+	default:
+		return true
+	}
+}
+
+// backoff computes the delay ahead of the given retry attempt (1-indexed)
+// using exponential backoff bounded by policy.MinRetryDelay and
+// policy.MaxRetryDelay, optionally randomized with full jitter.
+func backoff(policy RetryPolicy, attempt int) time.Duration {
+	delay := policy.MinRetryDelay << uint(attempt-1)
+	if delay <= 0 || delay > policy.MaxRetryDelay {
+		delay = policy.MaxRetryDelay
+	}
+
+	if policy.Jitter {
+		delay = time.Duration(rand.Int63n(int64(delay) + 1))
+	}
+
+	return delay
+}
+
+// retryAfter parses the Retry-After value captured on the error, if any,
+// supporting both the delta-seconds and HTTP-date forms defined by RFC
+// 9110 section 10.2.3.
+func (e *Error) retryAfter() (time.Duration, bool) {
+	if e == nil || e.rawRetryAfter == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(e.rawRetryAfter); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(e.rawRetryAfter); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,145 @@
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MinRetryDelay: 10 * time.Millisecond, MaxRetryDelay: 80 * time.Millisecond}
+
+	if got := backoff(policy, 1); got != 10*time.Millisecond {
+		t.Errorf("attempt 1: got %s, want %s", got, 10*time.Millisecond)
+	}
+	if got := backoff(policy, 2); got != 20*time.Millisecond {
+		t.Errorf("attempt 2: got %s, want %s", got, 20*time.Millisecond)
+	}
+	if got := backoff(policy, 10); got != 80*time.Millisecond {
+		t.Errorf("attempt 10: got %s, want capped %s", got, 80*time.Millisecond)
+	}
+}
+
+func TestRetryableMethod(t *testing.T) {
+	tests := []struct {
+		method        string
+		bodyWritten   bool
+		hasRetryAfter bool
+		want          bool
+	}{
+		{http.MethodGet, true, false, true},
+		{http.MethodPost, false, false, true},
+		{http.MethodPost, true, false, false},
+		{http.MethodPost, true, true, true},
+		{http.MethodPatch, true, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := retryableMethod(tt.method, tt.bodyWritten, tt.hasRetryAfter); got != tt.want {
+			t.Errorf("retryableMethod(%s, %v, %v) = %v, want %v", tt.method, tt.bodyWritten, tt.hasRetryAfter, got, tt.want)
+		}
+	}
+}
+
+func TestErrorRetryAfter(t *testing.T) {
+	e := &Error{rawRetryAfter: "2"}
+	d, ok := e.retryAfter()
+	if !ok || d != 2*time.Second {
+		t.Fatalf("delta-seconds: got %s, %v, want 2s, true", d, ok)
+	}
+
+	future := time.Now().Add(5 * time.Second).UTC().Format(http.TimeFormat)
+	e = &Error{rawRetryAfter: future}
+	d, ok = e.retryAfter()
+	if !ok || d <= 0 || d > 5*time.Second {
+		t.Fatalf("HTTP-date: got %s, %v, want ~5s, true", d, ok)
+	}
+
+	e = &Error{}
+	if _, ok := e.retryAfter(); ok {
+		t.Fatal("no Retry-After header: want ok=false")
+	}
+}
+
+// TestMakeRequestContextRetriesServiceError verifies that makeRequestContext
+// actually retries a 503 ServiceError via the real HTTP round trip, rather
+// than the retry loop only being reachable in isolation.
+func TestMakeRequestContextRetriesServiceError(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"success":false,"errors":[{"code":1,"message":"temporarily unavailable"}]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer srv.Close()
+
+	api, err := New("key", "email@example.com", WithRetryPolicy(RetryPolicy{
+		MaxRetries:    2,
+		MinRetryDelay: time.Millisecond,
+		MaxRetryDelay: 5 * time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.BaseURL = srv.URL
+
+	if _, err := api.makeRequestContext(context.Background(), http.MethodGet, "/zones", nil); err != nil {
+		t.Fatalf("makeRequestContext: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("expected 1 retry (2 calls), got %d calls", got)
+	}
+}
+
+// TestMakeRequestContextHonoursRetryAfter verifies that a 429 response
+// carrying a Retry-After header skips the usual exponential backoff in
+// favour of the requested delay, and that a POST is retried because the
+// response explicitly asked for it.
+func TestMakeRequestContextHonoursRetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"success":false,"errors":[{"code":2,"message":"slow down"}]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer srv.Close()
+
+	api, err := New("key", "email@example.com", WithRetryPolicy(RetryPolicy{
+		MaxRetries:    1,
+		MinRetryDelay: time.Minute,
+		MaxRetryDelay: time.Minute,
+	}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.BaseURL = srv.URL
+
+	start := time.Now()
+	if _, err := api.makeRequestContext(context.Background(), http.MethodPost, "/zones", map[string]string{"name": "example.com"}); err != nil {
+		t.Fatalf("makeRequestContext: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("retry took %s; Retry-After: 0 should have skipped the minute-long backoff", elapsed)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("expected 1 retry (2 calls), got %d calls", got)
+	}
+}
@@ -0,0 +1,196 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyRoundTripper fails the first remainingFailures round trips with a
+// plain transport-level error (no HTTP response at all), then forwards to
+// next. When readBody is true it drains the request body before failing,
+// simulating a transport error that occurred after bytes were written.
+type flakyRoundTripper struct {
+	remainingFailures int32
+	readBody          bool
+	next              http.RoundTripper
+
+	calls int32
+}
+
+func (rt *flakyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&rt.calls, 1)
+
+	if atomic.AddInt32(&rt.remainingFailures, -1) >= 0 {
+		if rt.readBody && req.Body != nil {
+			io.Copy(io.Discard, req.Body)
+		}
+		return nil, errors.New("simulated transport failure")
+	}
+
+	return rt.next.RoundTrip(req)
+}
+
+func newOKServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"success":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestMakeRequestContextRetriesTransportErrorOnIdempotentMethod verifies
+// that a plain transport-level failure (no HTTP response at all) on an
+// idempotent GET is retried, not just a typed RatelimitError/ServiceError.
+func TestMakeRequestContextRetriesTransportErrorOnIdempotentMethod(t *testing.T) {
+	srv := newOKServer(t)
+
+	rt := &flakyRoundTripper{remainingFailures: 1, next: http.DefaultTransport}
+	api, err := New("key", "email@example.com",
+		WithHTTPClient(&http.Client{Transport: rt}),
+		WithRetryPolicy(RetryPolicy{MaxRetries: 1, MinRetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.BaseURL = srv.URL
+
+	if _, err := api.makeRequestContext(context.Background(), http.MethodGet, "/zones", nil); err != nil {
+		t.Fatalf("makeRequestContext: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&rt.calls); got != 2 {
+		t.Fatalf("expected the transport failure to be retried (2 calls), got %d", got)
+	}
+}
+
+// TestMakeRequestContextSkipsRetryAfterBodyWritten verifies that a
+// non-idempotent POST is NOT retried after a transport failure once bytes
+// of the request body have already reached the transport.
+func TestMakeRequestContextSkipsRetryAfterBodyWritten(t *testing.T) {
+	srv := newOKServer(t)
+
+	rt := &flakyRoundTripper{remainingFailures: 1, readBody: true, next: http.DefaultTransport}
+	api, err := New("key", "email@example.com",
+		WithHTTPClient(&http.Client{Transport: rt}),
+		WithRetryPolicy(RetryPolicy{MaxRetries: 2, MinRetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.BaseURL = srv.URL
+
+	_, reqErr := api.makeRequestContext(context.Background(), http.MethodPost, "/zones", map[string]string{"name": "example.com"})
+	if reqErr == nil {
+		t.Fatal("expected an error; a POST whose body was already written should not be silently retried")
+	}
+
+	if got := atomic.LoadInt32(&rt.calls); got != 1 {
+		t.Fatalf("expected exactly 1 attempt (no retry), got %d calls", got)
+	}
+}
+
+// TestMakeRequestContextRetriesBeforeBodyWritten verifies that a POST IS
+// retried when the transport failure happened before any bytes of the
+// body were sent.
+func TestMakeRequestContextRetriesBeforeBodyWritten(t *testing.T) {
+	srv := newOKServer(t)
+
+	rt := &flakyRoundTripper{remainingFailures: 1, readBody: false, next: http.DefaultTransport}
+	api, err := New("key", "email@example.com",
+		WithHTTPClient(&http.Client{Transport: rt}),
+		WithRetryPolicy(RetryPolicy{MaxRetries: 1, MinRetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.BaseURL = srv.URL
+
+	if _, err := api.makeRequestContext(context.Background(), http.MethodPost, "/zones", map[string]string{"name": "example.com"}); err != nil {
+		t.Fatalf("makeRequestContext: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&rt.calls); got != 2 {
+		t.Fatalf("expected the pre-write transport failure to be retried (2 calls), got %d", got)
+	}
+}
+
+// TestMakeRequestContextIgnoresStrayRetryAfterOn500 verifies that a 500
+// response carrying a Retry-After header (Cloudflare only documents it for
+// 429/503) does not license retrying a non-idempotent POST.
+func TestMakeRequestContextIgnoresStrayRetryAfterOn500(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"success":false,"errors":[{"code":1,"message":"internal error"}]}`))
+	}))
+	defer srv.Close()
+
+	api, err := New("key", "email@example.com", WithRetryPolicy(RetryPolicy{
+		MaxRetries: 2, MinRetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.BaseURL = srv.URL
+
+	_, reqErr := api.makeRequestContext(context.Background(), http.MethodPost, "/zones", map[string]string{"name": "example.com"})
+	if reqErr == nil {
+		t.Fatal("expected an error")
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected exactly 1 attempt (stray Retry-After on 500 must not retry a POST), got %d calls", got)
+	}
+}
+
+// TestMakeRequestContextHonoursRetryAfterOn503 verifies that a 503 (the
+// status Cloudflare documents emitting Retry-After for) DOES license
+// retrying a non-idempotent POST.
+func TestMakeRequestContextHonoursRetryAfterOn503(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"success":false,"errors":[{"code":1,"message":"unavailable"}]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer srv.Close()
+
+	api, err := New("key", "email@example.com", WithRetryPolicy(RetryPolicy{
+		MaxRetries: 1, MinRetryDelay: time.Minute, MaxRetryDelay: time.Minute,
+	}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	api.BaseURL = srv.URL
+
+	start := time.Now()
+	if _, err := api.makeRequestContext(context.Background(), http.MethodPost, "/zones", map[string]string{"name": "example.com"}); err != nil {
+		t.Fatalf("makeRequestContext: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("retry took %s; Retry-After: 0 on a 503 should have skipped the minute-long backoff", elapsed)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("expected 1 retry (2 calls), got %d calls", got)
+	}
+}